@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pasteSizeDesc = prometheus.NewDesc(
+		"delerium_paste_size_bytes",
+		"Distribution of paste sizes in bytes",
+		[]string{"instance"}, nil,
+	)
+	pasteTTLDesc = prometheus.NewDesc(
+		"delerium_paste_ttl_seconds",
+		"Distribution of paste TTLs in seconds",
+		[]string{"instance"}, nil,
+	)
+	chatMessageLengthDesc = prometheus.NewDesc(
+		"delerium_chat_message_length_bytes",
+		"Distribution of chat message lengths in bytes",
+		[]string{"instance"}, nil,
+	)
+	pastesByLanguageDesc = prometheus.NewDesc(
+		"delerium_pastes_by_language",
+		"Current number of pastes by syntax-highlight language",
+		[]string{"instance", "language"}, nil,
+	)
+	pastesByExpiryBucketDesc = prometheus.NewDesc(
+		"delerium_pastes_by_expiry_bucket",
+		"Current number of pastes by expiry bucket",
+		[]string{"instance", "ttl_bucket"}, nil,
+	)
+)
+
+// aggregateStats is the subset of InternalStats that gets translated into
+// native Prometheus histogram/counter families rather than plain gauges.
+type aggregateStats struct {
+	PasteSizeBytes       *HistogramStats
+	PasteTTLSeconds      *HistogramStats
+	ChatMessageLength    *HistogramStats
+	PastesByLanguage     map[string]int64
+	PastesByExpiryBucket map[string]int64
+}
+
+// aggregateCollector exposes pre-aggregated per-instance histogram and
+// counter data as native Prometheus metrics. Unlike the delerium_* gauges
+// in Metrics, these are built with NewConstHistogram/NewConstMetric on
+// every Collect call rather than accumulated in a Gauge/CounterVec, since
+// the underlying data already arrives as finished buckets and counts.
+type aggregateCollector struct {
+	mu     sync.RWMutex
+	byInst map[string]aggregateStats
+}
+
+func newAggregateCollector() *aggregateCollector {
+	return &aggregateCollector{byInst: make(map[string]aggregateStats)}
+}
+
+// observe replaces the cached aggregate stats for instance. Instances the
+// server doesn't report on (old servers, omitted fields) simply never
+// appear here and contribute no series.
+func (c *aggregateCollector) observe(instance string, stats aggregateStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byInst[instance] = stats
+}
+
+func (c *aggregateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pasteSizeDesc
+	ch <- pasteTTLDesc
+	ch <- chatMessageLengthDesc
+	ch <- pastesByLanguageDesc
+	ch <- pastesByExpiryBucketDesc
+}
+
+func (c *aggregateCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for instance, stats := range c.byInst {
+		emitHistogram(ch, pasteSizeDesc, stats.PasteSizeBytes, instance)
+		emitHistogram(ch, pasteTTLDesc, stats.PasteTTLSeconds, instance)
+		emitHistogram(ch, chatMessageLengthDesc, stats.ChatMessageLength, instance)
+
+		for language, count := range stats.PastesByLanguage {
+			ch <- prometheus.MustNewConstMetric(pastesByLanguageDesc, prometheus.CounterValue, float64(count), instance, language)
+		}
+		for bucket, count := range stats.PastesByExpiryBucket {
+			ch <- prometheus.MustNewConstMetric(pastesByExpiryBucketDesc, prometheus.CounterValue, float64(count), instance, bucket)
+		}
+	}
+}
+
+// emitHistogram turns a pre-aggregated HistogramStats into a single
+// Prometheus histogram metric (_bucket/_sum/_count with "le" labels). It
+// is a no-op when hist is nil, which is how an older server that never
+// sends the field stays backward compatible.
+func emitHistogram(ch chan<- prometheus.Metric, desc *prometheus.Desc, hist *HistogramStats, instance string) {
+	if hist == nil {
+		return
+	}
+
+	buckets := make(map[float64]uint64, len(hist.Buckets))
+	for le, count := range hist.Buckets {
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			log.Printf("Skipping histogram bucket with non-numeric le %q for %s", le, desc)
+			continue
+		}
+		if math.IsInf(bound, 1) {
+			// NewConstHistogram adds the +Inf bucket itself from hist.Count;
+			// including it here would duplicate the le="+Inf" series.
+			continue
+		}
+		buckets[bound] = count
+	}
+
+	metric, err := prometheus.NewConstHistogram(desc, hist.Count, hist.Sum, buckets, instance)
+	if err != nil {
+		log.Printf("Error constructing histogram metric for %s: %v", desc, err)
+		return
+	}
+	ch <- metric
+}
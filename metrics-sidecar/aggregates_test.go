@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectOne drains a single metric sent to ch, or returns nil if emitHistogram
+// was a no-op.
+func collectOne(fn func(ch chan prometheus.Metric)) *dto.Metric {
+	ch := make(chan prometheus.Metric, 1)
+	fn(ch)
+	close(ch)
+
+	metric, ok := <-ch
+	if !ok {
+		return nil
+	}
+
+	out := &dto.Metric{}
+	if err := metric.Write(out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func TestEmitHistogramNilIsNoOp(t *testing.T) {
+	got := collectOne(func(ch chan prometheus.Metric) {
+		emitHistogram(ch, pasteSizeDesc, nil, "prod")
+	})
+	if got != nil {
+		t.Errorf("emitHistogram(nil) sent a metric, want no-op: %+v", got)
+	}
+}
+
+func TestEmitHistogramSkipsNonNumericBucket(t *testing.T) {
+	hist := &HistogramStats{
+		Buckets: map[string]uint64{
+			"100":          5,
+			"not-a-number": 9,
+		},
+		Sum:   42,
+		Count: 5,
+	}
+
+	out := collectOne(func(ch chan prometheus.Metric) {
+		emitHistogram(ch, pasteSizeDesc, hist, "prod")
+	})
+	if out == nil {
+		t.Fatal("emitHistogram sent no metric")
+	}
+
+	h := out.GetHistogram()
+	if h == nil {
+		t.Fatal("metric has no histogram payload")
+	}
+
+	for _, b := range h.GetBucket() {
+		if b.GetUpperBound() == 0 && b.GetCumulativeCount() == 9 {
+			t.Errorf("non-numeric bucket %q leaked into output: %+v", "not-a-number", b)
+		}
+	}
+	if got := len(h.GetBucket()); got != 1 {
+		t.Errorf("len(buckets) = %d, want 1 (non-numeric le skipped)", got)
+	}
+}
+
+func TestEmitHistogramSkipsPositiveInfBucket(t *testing.T) {
+	hist := &HistogramStats{
+		Buckets: map[string]uint64{
+			"100":  5,
+			"+Inf": 7,
+		},
+		Sum:   42,
+		Count: 7,
+	}
+
+	out := collectOne(func(ch chan prometheus.Metric) {
+		emitHistogram(ch, pasteSizeDesc, hist, "prod")
+	})
+	if out == nil {
+		t.Fatal("emitHistogram sent no metric")
+	}
+
+	h := out.GetHistogram()
+	if h == nil {
+		t.Fatal("metric has no histogram payload")
+	}
+
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			t.Errorf("+Inf bucket was included explicitly, duplicating NewConstHistogram's implicit one: %+v", b)
+		}
+	}
+	if got := len(h.GetBucket()); got != 1 {
+		t.Errorf("len(buckets) = %d, want 1 (+Inf skipped)", got)
+	}
+	if got := h.GetSampleCount(); got != 7 {
+		t.Errorf("sample count = %d, want 7", got)
+	}
+}
+
+func TestEmitHistogramNormalBuckets(t *testing.T) {
+	hist := &HistogramStats{
+		Buckets: map[string]uint64{
+			"100":  3,
+			"1000": 8,
+		},
+		Sum:   4200,
+		Count: 8,
+	}
+
+	out := collectOne(func(ch chan prometheus.Metric) {
+		emitHistogram(ch, pasteSizeDesc, hist, "prod")
+	})
+	if out == nil {
+		t.Fatal("emitHistogram sent no metric")
+	}
+
+	h := out.GetHistogram()
+	if h == nil {
+		t.Fatal("metric has no histogram payload")
+	}
+
+	wantBounds := map[float64]uint64{100: 3, 1000: 8}
+	if got := len(h.GetBucket()); got != len(wantBounds) {
+		t.Fatalf("len(buckets) = %d, want %d", got, len(wantBounds))
+	}
+	for _, b := range h.GetBucket() {
+		want, ok := wantBounds[b.GetUpperBound()]
+		if !ok {
+			t.Errorf("unexpected bucket bound %v", b.GetUpperBound())
+			continue
+		}
+		if b.GetCumulativeCount() != want {
+			t.Errorf("bucket %v cumulative count = %d, want %d", b.GetUpperBound(), b.GetCumulativeCount(), want)
+		}
+	}
+	if got := h.GetSampleSum(); got != 4200 {
+		t.Errorf("sample sum = %v, want 4200", got)
+	}
+	if got := h.GetSampleCount(); got != 8 {
+		t.Errorf("sample count = %d, want 8", got)
+	}
+
+	if got := out.GetLabel(); len(got) != 1 || got[0].GetValue() != "prod" {
+		t.Errorf("labels = %+v, want instance=prod", got)
+	}
+}
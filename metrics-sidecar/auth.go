@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authenticator decides whether a request may proceed to /metrics. It may
+// write a challenge or error response itself before returning false.
+type Authenticator interface {
+	Authenticate(w http.ResponseWriter, r *http.Request) bool
+}
+
+// buildAuthenticator selects an Authenticator based on METRICS_AUTH_MODE:
+//
+//	"basic" (default) - htpasswd file (METRICS_HTPASSWD_FILE) if set,
+//	                     otherwise the single METRICS_USER/METRICS_PASS pair
+//	"bearer"           - a static token (METRICS_BEARER_TOKEN) or a tokens
+//	                     file (METRICS_BEARER_TOKENS_FILE), one per line
+//	"mtls"             - client certificate auth; the caller must also serve
+//	                     TLS with the *tls.Config returned here
+//
+// It returns the Authenticator and, for "mtls", a *tls.Config the caller
+// must use when starting the HTTPS listener (nil otherwise).
+func buildAuthenticator() (Authenticator, *tls.Config, error) {
+	mode := strings.ToLower(os.Getenv("METRICS_AUTH_MODE"))
+	if mode == "" {
+		mode = "basic"
+	}
+
+	switch mode {
+	case "basic":
+		if path := os.Getenv("METRICS_HTPASSWD_FILE"); path != "" {
+			auth, err := newHtpasswdAuthenticator(path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load htpasswd file: %w", err)
+			}
+			return auth, nil, nil
+		}
+		return &basicAuthenticator{
+			user: os.Getenv("METRICS_USER"),
+			pass: os.Getenv("METRICS_PASS"),
+		}, nil, nil
+
+	case "bearer":
+		tokens, err := loadBearerTokens()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load bearer tokens: %w", err)
+		}
+		return &bearerAuthenticator{tokens: tokens}, nil, nil
+
+	case "mtls":
+		caPath := os.Getenv("METRICS_CLIENT_CA")
+		if caPath == "" {
+			return nil, nil, fmt.Errorf("METRICS_AUTH_MODE=mtls requires METRICS_CLIENT_CA")
+		}
+		tlsConfig, err := clientCATLSConfig(caPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load client CA: %w", err)
+		}
+		return &clientCertAuthenticator{}, tlsConfig, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown METRICS_AUTH_MODE %q", mode)
+	}
+}
+
+// basicAuthenticator is the original single-user HTTP Basic Auth check,
+// kept as the fallback when no htpasswd file is configured.
+type basicAuthenticator struct {
+	user string
+	pass string
+}
+
+func (a *basicAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) bool {
+	if a.user == "" || a.pass == "" {
+		log.Printf("WARNING: METRICS_USER and METRICS_PASS not configured - rejecting request")
+		challenge(w)
+		http.Error(w, "Metrics authentication not configured", http.StatusUnauthorized)
+		return false
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		challenge(w)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return false
+	}
+
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(a.user)) == 1
+	passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(a.pass)) == 1
+	if !userMatch || !passMatch {
+		challenge(w)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func challenge(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+}
+
+// bearerAuthenticator checks the Authorization: Bearer <token> header
+// against a fixed set of accepted tokens, each compared in constant time.
+type bearerAuthenticator struct {
+	tokens map[string]struct{}
+}
+
+func loadBearerTokens() (map[string]struct{}, error) {
+	tokens := make(map[string]struct{})
+
+	if token := os.Getenv("METRICS_BEARER_TOKEN"); token != "" {
+		tokens[token] = struct{}{}
+	}
+
+	if path := os.Getenv("METRICS_BEARER_TOKENS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			tokens[line] = struct{}{}
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("METRICS_AUTH_MODE=bearer requires METRICS_BEARER_TOKEN or METRICS_BEARER_TOKENS_FILE")
+	}
+
+	return tokens, nil
+}
+
+func (a *bearerAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) bool {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "Bearer token required", http.StatusUnauthorized)
+		return false
+	}
+	presented := strings.TrimPrefix(header, prefix)
+
+	for token := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			return true
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+	http.Error(w, "Invalid token", http.StatusUnauthorized)
+	return false
+}
+
+// clientCertAuthenticator trusts the TLS layer: by the time a request
+// reaches here, tls.Config.ClientAuth = RequireAndVerifyClientCert has
+// already verified the client certificate against the configured CA pool.
+// This only guards against being mounted behind a non-TLS listener.
+type clientCertAuthenticator struct{}
+
+func (a *clientCertAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		http.Error(w, "Client certificate required", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// clientCATLSConfig builds a server TLS config that requires and verifies
+// a client certificate signed by the CA(s) in caPath.
+func clientCATLSConfig(caPath string) (*tls.Config, error) {
+	caCert, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in %s", caPath)
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}, nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildAuthenticator(t *testing.T) {
+	t.Run("defaults to basic", func(t *testing.T) {
+		auth, tlsConfig, err := buildAuthenticator()
+		if err != nil {
+			t.Fatalf("buildAuthenticator() error = %v", err)
+		}
+		if _, ok := auth.(*basicAuthenticator); !ok {
+			t.Errorf("got %T, want *basicAuthenticator", auth)
+		}
+		if tlsConfig != nil {
+			t.Errorf("got non-nil tlsConfig for basic mode")
+		}
+	})
+
+	t.Run("basic with htpasswd file", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "basic")
+
+		path := filepath.Join(t.TempDir(), "htpasswd")
+		if err := os.WriteFile(path, []byte("alice:secret\n"), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		t.Setenv("METRICS_HTPASSWD_FILE", path)
+
+		auth, _, err := buildAuthenticator()
+		if err != nil {
+			t.Fatalf("buildAuthenticator() error = %v", err)
+		}
+		if _, ok := auth.(*htpasswdAuthenticator); !ok {
+			t.Errorf("got %T, want *htpasswdAuthenticator", auth)
+		}
+	})
+
+	t.Run("basic with missing htpasswd file errors", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "basic")
+		t.Setenv("METRICS_HTPASSWD_FILE", filepath.Join(t.TempDir(), "does-not-exist"))
+
+		if _, _, err := buildAuthenticator(); err == nil {
+			t.Error("buildAuthenticator() error = nil, want error for missing htpasswd file")
+		}
+	})
+
+	t.Run("bearer with token", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "bearer")
+		t.Setenv("METRICS_BEARER_TOKEN", "tok123")
+
+		auth, _, err := buildAuthenticator()
+		if err != nil {
+			t.Fatalf("buildAuthenticator() error = %v", err)
+		}
+		if _, ok := auth.(*bearerAuthenticator); !ok {
+			t.Errorf("got %T, want *bearerAuthenticator", auth)
+		}
+	})
+
+	t.Run("bearer without any token errors", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "bearer")
+
+		if _, _, err := buildAuthenticator(); err == nil {
+			t.Error("buildAuthenticator() error = nil, want error for missing tokens")
+		}
+	})
+
+	t.Run("mtls with client CA", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "mtls")
+
+		caPath := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(caPath, []byte(testCACertPEM), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		t.Setenv("METRICS_CLIENT_CA", caPath)
+
+		auth, tlsConfig, err := buildAuthenticator()
+		if err != nil {
+			t.Fatalf("buildAuthenticator() error = %v", err)
+		}
+		if _, ok := auth.(*clientCertAuthenticator); !ok {
+			t.Errorf("got %T, want *clientCertAuthenticator", auth)
+		}
+		if tlsConfig == nil {
+			t.Fatal("got nil tlsConfig for mtls mode")
+		}
+		if tlsConfig.ClientCAs == nil {
+			t.Error("tlsConfig.ClientCAs is nil")
+		}
+	})
+
+	t.Run("mtls without client CA errors", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "mtls")
+
+		if _, _, err := buildAuthenticator(); err == nil {
+			t.Error("buildAuthenticator() error = nil, want error for missing METRICS_CLIENT_CA")
+		}
+	})
+
+	t.Run("unknown mode errors", func(t *testing.T) {
+		t.Setenv("METRICS_AUTH_MODE", "hmac")
+
+		if _, _, err := buildAuthenticator(); err == nil {
+			t.Error("buildAuthenticator() error = nil, want error for unknown mode")
+		}
+	})
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	cases := []struct {
+		name       string
+		auth       *basicAuthenticator
+		reqUser    string
+		reqPass    string
+		sendHeader bool
+		wantOK     bool
+	}{
+		{
+			name:       "not configured rejects",
+			auth:       &basicAuthenticator{},
+			reqUser:    "alice",
+			reqPass:    "secret",
+			sendHeader: true,
+			wantOK:     false,
+		},
+		{
+			name:       "missing credentials rejected",
+			auth:       &basicAuthenticator{user: "alice", pass: "secret"},
+			sendHeader: false,
+			wantOK:     false,
+		},
+		{
+			name:       "wrong password rejected",
+			auth:       &basicAuthenticator{user: "alice", pass: "secret"},
+			reqUser:    "alice",
+			reqPass:    "wrong",
+			sendHeader: true,
+			wantOK:     false,
+		},
+		{
+			name:       "wrong user rejected",
+			auth:       &basicAuthenticator{user: "alice", pass: "secret"},
+			reqUser:    "bob",
+			reqPass:    "secret",
+			sendHeader: true,
+			wantOK:     false,
+		},
+		{
+			name:       "correct credentials accepted",
+			auth:       &basicAuthenticator{user: "alice", pass: "secret"},
+			reqUser:    "alice",
+			reqPass:    "secret",
+			sendHeader: true,
+			wantOK:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.sendHeader {
+				req.SetBasicAuth(tc.reqUser, tc.reqPass)
+			}
+			w := httptest.NewRecorder()
+
+			if got := tc.auth.Authenticate(w, req); got != tc.wantOK {
+				t.Errorf("Authenticate() = %v, want %v", got, tc.wantOK)
+			}
+			if !tc.wantOK && w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestLoadBearerTokens(t *testing.T) {
+	t.Run("no token configured errors", func(t *testing.T) {
+		if _, err := loadBearerTokens(); err == nil {
+			t.Error("loadBearerTokens() error = nil, want error")
+		}
+	})
+
+	t.Run("single token via env", func(t *testing.T) {
+		t.Setenv("METRICS_BEARER_TOKEN", "tok123")
+
+		tokens, err := loadBearerTokens()
+		if err != nil {
+			t.Fatalf("loadBearerTokens() error = %v", err)
+		}
+		if _, ok := tokens["tok123"]; !ok || len(tokens) != 1 {
+			t.Errorf("tokens = %v, want {tok123}", tokens)
+		}
+	})
+
+	t.Run("multiple tokens via file, blank lines and comments ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens")
+		contents := "tok-a\n\n# a comment\ntok-b\n  \ntok-c\n"
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		t.Setenv("METRICS_BEARER_TOKENS_FILE", path)
+
+		tokens, err := loadBearerTokens()
+		if err != nil {
+			t.Fatalf("loadBearerTokens() error = %v", err)
+		}
+		for _, want := range []string{"tok-a", "tok-b", "tok-c"} {
+			if _, ok := tokens[want]; !ok {
+				t.Errorf("tokens missing %q: %v", want, tokens)
+			}
+		}
+		if len(tokens) != 3 {
+			t.Errorf("len(tokens) = %d, want 3", len(tokens))
+		}
+	})
+
+	t.Run("missing tokens file errors", func(t *testing.T) {
+		t.Setenv("METRICS_BEARER_TOKENS_FILE", filepath.Join(t.TempDir(), "does-not-exist"))
+
+		if _, err := loadBearerTokens(); err == nil {
+			t.Error("loadBearerTokens() error = nil, want error for missing file")
+		}
+	})
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	auth := &bearerAuthenticator{tokens: map[string]struct{}{"tok-a": {}, "tok-b": {}}}
+
+	cases := []struct {
+		name   string
+		header string
+		wantOK bool
+	}{
+		{name: "missing header rejected", header: "", wantOK: false},
+		{name: "missing Bearer prefix rejected", header: "tok-a", wantOK: false},
+		{name: "wrong token rejected", header: "Bearer tok-wrong", wantOK: false},
+		{name: "correct token accepted", header: "Bearer tok-a", wantOK: true},
+		{name: "other correct token accepted", header: "Bearer tok-b", wantOK: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			if got := auth.Authenticate(w, req); got != tc.wantOK {
+				t.Errorf("Authenticate() = %v, want %v", got, tc.wantOK)
+			}
+			if !tc.wantOK && w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	auth := &clientCertAuthenticator{}
+
+	t.Run("no TLS connection state rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		w := httptest.NewRecorder()
+
+		if got := auth.Authenticate(w, req); got {
+			t.Error("Authenticate() = true, want false without a client certificate")
+		}
+	})
+}
+
+// testCACertPEM is a self-signed certificate usable as a client CA pool
+// member; clientCATLSConfig only needs it to parse, it never validates an
+// actual client certificate in these tests.
+const testCACertPEM = `-----BEGIN CERTIFICATE-----
+MIIBeTCCAR+gAwIBAgIUG7/tGP+Wmh9cLOr8unKagjMRpKwwCgYIKoZIzj0EAwIw
+EjEQMA4GA1UECgwHVGVzdCBDQTAeFw0yNjA3MjUxOTQ2MThaFw0zNjA3MjIxOTQ2
+MThaMBIxEDAOBgNVBAoMB1Rlc3QgQ0EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNC
+AAQPFFZEbrW/tl0/4Cme3RqDyMOsMbiZn8AYQFwpEeEc808x/xs958MTIkZZP9mJ
+pXHJsVJrNSsI5O/KOBHxAzZko1MwUTAdBgNVHQ4EFgQUS3G0Iu/teu4y8uhHyMF+
+cu/OWBIwHwYDVR0jBBgwFoAUS3G0Iu/teu4y8uhHyMF+cu/OWBIwDwYDVR0TAQH/
+BAUwAwEB/zAKBggqhkjOPQQDAgNIADBFAiBkpyozE3nRF+tefQfukbI5gxzHIhiN
+0hzDFwP/YauTHwIhAPzJ4GV3NruAgvLhL3nQEtscltdvps197kEk9X/2KjAb
+-----END CERTIFICATE-----`
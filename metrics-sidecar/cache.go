@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cachedScraper memoizes the result of Scraper.ScrapeAll for ttl, so that
+// /metrics and the /ready upstream check can share a single fetch per
+// interval instead of each hammering every backend on every request.
+type cachedScraper struct {
+	scraper      *Scraper
+	ttl          time.Duration
+	fetchTimeout time.Duration
+
+	mu      sync.Mutex
+	results []ScrapeResult
+	at      time.Time
+}
+
+func newCachedScraper(scraper *Scraper, ttl, fetchTimeout time.Duration) *cachedScraper {
+	return &cachedScraper{scraper: scraper, ttl: ttl, fetchTimeout: fetchTimeout}
+}
+
+// Scrape returns the cached results if they are newer than ttl, otherwise
+// it fetches fresh ones and updates the cache. The fetch that refreshes
+// the cache always runs on its own background context (bounded by
+// fetchTimeout), never on the context of whichever caller happened to
+// trigger the miss - a client disconnecting from /metrics, or /ready's own
+// check deadline, must not cancel the single in-flight fetch that every
+// other waiter is also relying on.
+func (c *cachedScraper) Scrape(ctx context.Context) []ScrapeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.results != nil && time.Since(c.at) < c.ttl {
+		return c.results
+	}
+
+	fetchCtx, cancel := context.WithTimeout(context.Background(), c.fetchTimeout)
+	defer cancel()
+
+	c.results = c.scraper.ScrapeAll(fetchCtx)
+	c.at = time.Now()
+	return c.results
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check is one named readiness check. Check.Run is invoked every Interval,
+// bounded by Timeout, and its last result is cached for Checker.Results.
+type Check struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// CheckResult is the last outcome recorded for a Check.
+type CheckResult struct {
+	Err         error     `json:"-"`
+	Message     string    `json:"message,omitempty"`
+	LastChecked time.Time `json:"lastChecked"`
+}
+
+// Checker runs a set of Checks on their own schedules and caches the last
+// result of each, similar in spirit to go-sundheit's health.Health.
+type Checker struct {
+	checks []Check
+
+	mu      sync.RWMutex
+	results map[string]CheckResult
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		results: make(map[string]CheckResult, len(checks)),
+	}
+}
+
+// Run starts a goroutine per check that re-evaluates it on its interval
+// until ctx is canceled. Each check is run once immediately so /ready has
+// a result to report right away.
+func (c *Checker) Run(ctx context.Context) {
+	for _, check := range c.checks {
+		check := check
+		go c.loop(ctx, check)
+	}
+}
+
+func (c *Checker) loop(ctx context.Context, check Check) {
+	c.evaluate(ctx, check)
+
+	ticker := time.NewTicker(check.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.evaluate(ctx, check)
+		}
+	}
+}
+
+func (c *Checker) evaluate(ctx context.Context, check Check) {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	err := check.Run(ctx)
+
+	result := CheckResult{Err: err, LastChecked: time.Now()}
+	if err != nil {
+		result.Message = err.Error()
+	}
+
+	c.mu.Lock()
+	c.results[check.Name] = result
+	c.mu.Unlock()
+}
+
+// Results returns a snapshot of the last result for every registered check.
+func (c *Checker) Results() map[string]CheckResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string]CheckResult, len(c.results))
+	for name, result := range c.results {
+		out[name] = result
+	}
+	return out
+}
+
+// Ready reports whether every registered check last succeeded.
+func (c *Checker) Ready() (bool, map[string]CheckResult) {
+	results := c.Results()
+	for _, result := range results {
+		if result.Err != nil {
+			return false, results
+		}
+	}
+	return true, results
+}
+
+type readyResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// readyHandler reports whether the sidecar can serve traffic: every
+// registered Check must have last succeeded. Failing checks are listed in
+// the JSON body alongside a 503.
+func readyHandler(checker *Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ready, results := checker.Ready()
+
+		w.Header().Set("Content-Type", "application/json")
+		resp := readyResponse{Checks: results}
+		if ready {
+			resp.Status = "ok"
+			w.WriteHeader(http.StatusOK)
+		} else {
+			resp.Status = "unavailable"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// liveHandler reports only that the process is up and serving requests.
+func liveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(readyResponse{Status: "ok"})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tg123/go-htpasswd"
+)
+
+// htpasswdAuthenticator validates Basic Auth credentials against an
+// htpasswd file (bcrypt, SHA, and apr1 hashes), reloading it whenever the
+// file changes on disk so credentials can be rotated without a restart.
+type htpasswdAuthenticator struct {
+	path string
+
+	mu   sync.RWMutex
+	file *htpasswd.File
+}
+
+func newHtpasswdAuthenticator(path string) (*htpasswdAuthenticator, error) {
+	file, err := htpasswd.New(path, htpasswd.DefaultSystems, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &htpasswdAuthenticator{path: path, file: file}
+	go a.watch()
+	return a, nil
+}
+
+// watch reloads the htpasswd file whenever it changes on disk. It watches
+// the parent directory rather than the file itself: credential rotation in
+// the wild (Kubernetes Secret/ConfigMap mounts, "mv tmp final", htpasswd
+// tooling that writes-then-renames) replaces the file via an atomic
+// rename, which would silently kill an inode-based watch on the file
+// after the first rotation.
+func (a *htpasswdAuthenticator) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("WARNING: could not watch %s for changes: %v", a.path, err)
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(a.path)
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("WARNING: could not watch %s for changes: %v", dir, err)
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(a.path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			a.reload()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("WARNING: error watching %s for changes: %v", dir, err)
+		}
+	}
+}
+
+func (a *htpasswdAuthenticator) reload() {
+	file, err := htpasswd.New(a.path, htpasswd.DefaultSystems, nil)
+	if err != nil {
+		log.Printf("WARNING: failed to reload %s after change: %v", a.path, err)
+		return
+	}
+
+	a.mu.Lock()
+	a.file = file
+	a.mu.Unlock()
+	log.Printf("Reloaded htpasswd file %s", a.path)
+}
+
+func (a *htpasswdAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		challenge(w)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return false
+	}
+
+	a.mu.RLock()
+	file := a.file
+	a.mu.RUnlock()
+
+	if !file.Match(user, pass) {
+		challenge(w)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
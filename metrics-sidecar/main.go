@@ -1,180 +1,208 @@
 // Package main implements a Prometheus metrics exporter sidecar for Delirium Paste.
 //
-// This sidecar container fetches aggregate statistics from the main server's
-// internal endpoint and exposes them in Prometheus format. It runs in isolation
-// to maintain security boundaries - the metrics endpoint is separate from the
-// main API.
+// This sidecar container fetches aggregate statistics from one or more of the
+// main server's internal endpoints and exposes them in Prometheus format. It
+// runs in isolation to maintain security boundaries - the metrics endpoint is
+// separate from the main API.
 //
-// Authentication: Basic auth is required for the /metrics endpoint.
-// Set METRICS_USER and METRICS_PASS environment variables.
+// Backends: set SERVER_URL for a single instance, or SERVER_URLS for a
+// comma-separated list (optionally "name=url" pairs) to scrape several
+// instances in parallel, each exposed with an "instance" label. The /sd
+// endpoint exposes the same backends in the Prometheus HTTP SD JSON format.
+//
+// Push mode: set PUSHGATEWAY_URL (and optionally PUSH_INTERVAL, PUSH_INSTANCE)
+// to periodically push the same metrics to a Pushgateway in addition to
+// serving /metrics - for environments Prometheus cannot scrape.
+//
+// Authentication: selected by METRICS_AUTH_MODE ("basic", "bearer", or
+// "mtls", default "basic"). Basic mode checks an htpasswd file
+// (METRICS_HTPASSWD_FILE, live-reloaded) or falls back to the single
+// METRICS_USER/METRICS_PASS pair. Bearer mode checks METRICS_BEARER_TOKEN
+// or a METRICS_BEARER_TOKENS_FILE. Mtls mode requires a client certificate
+// signed by METRICS_CLIENT_CA and serves over TLS (METRICS_TLS_CERT_FILE,
+// METRICS_TLS_KEY_FILE). See auth.go for details.
+//
+// Aggregate detail: if the server's /internal/stats response includes the
+// optional histogram/counter fields (paste size, paste TTL, chat message
+// length, per-language and per-expiry-bucket counts), they are exposed as
+// native Prometheus histogram and counter families. Servers that omit them
+// only get the gauges above - see aggregates.go.
 //
 // Privacy: All metrics are aggregate only. No personal data, paste content,
 // IPs, or identifiable information is collected or exposed.
 package main
 
 import (
-	"crypto/subtle"
-	"encoding/json"
-	"fmt"
-	"io"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// InternalStats matches the JSON response from /internal/stats
-type InternalStats struct {
-	ActivePasteCount  int64 `json:"activePasteCount"`
-	TotalChatMessages int64 `json:"totalChatMessages"`
-	DatabaseHealthy   bool  `json:"databaseHealthy"`
-	TimestampMs       int64 `json:"timestampMs"`
-}
+var startTime time.Time
 
-var (
-	serverURL   string
-	startTime   time.Time
-	metricsUser string
-	metricsPass string
+const (
+	defaultScrapeTimeout = 5 * time.Second
+	defaultConcurrency   = 8
+	scrapeCacheTTL       = 10 * time.Second
+	shutdownGracePeriod  = 15 * time.Second
 )
 
 func init() {
-	serverURL = os.Getenv("SERVER_URL")
-	if serverURL == "" {
-		serverURL = "http://server:8080"
-	}
 	startTime = time.Now()
-
-	// Load authentication credentials
-	metricsUser = os.Getenv("METRICS_USER")
-	metricsPass = os.Getenv("METRICS_PASS")
 }
 
-// fetchStats retrieves stats from the main server's internal endpoint
-func fetchStats() (*InternalStats, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(serverURL + "/internal/stats")
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch stats: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// loadTargets builds the target list from SERVER_URLS, falling back to the
+// single-instance SERVER_URL (or its default) for backward compatibility.
+func loadTargets() []Target {
+	if raw := os.Getenv("SERVER_URLS"); raw != "" {
+		return parseTargets(raw)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	serverURL := os.Getenv("SERVER_URL")
+	if serverURL == "" {
+		serverURL = "http://server:8080"
 	}
+	return []Target{{Name: "default", URL: serverURL}}
+}
 
-	var stats InternalStats
-	if err := json.Unmarshal(body, &stats); err != nil {
-		return nil, fmt.Errorf("failed to parse stats: %w", err)
+func scrapeConcurrency() int {
+	if raw := os.Getenv("SCRAPE_CONCURRENCY"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
 	}
-
-	return &stats, nil
+	return defaultConcurrency
 }
 
-// basicAuth wraps a handler with HTTP Basic Authentication
-func basicAuth(next http.HandlerFunc) http.HandlerFunc {
+// requireAuth wraps a handler, rejecting requests the Authenticator does
+// not approve.
+func requireAuth(a Authenticator, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// If no credentials configured, require them to be set
-		if metricsUser == "" || metricsPass == "" {
-			log.Printf("WARNING: METRICS_USER and METRICS_PASS not configured - rejecting request")
-			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
-			http.Error(w, "Metrics authentication not configured", http.StatusUnauthorized)
+		if !a.Authenticate(w, r) {
 			return
 		}
+		next(w, r)
+	}
+}
 
-		user, pass, ok := r.BasicAuth()
-		if !ok {
-			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
-			return
-		}
+// collectHandler refreshes the delerium_* gauges from every configured
+// backend before handing off to the Prometheus registry's own HTTP handler.
+// It shares its fetch with the upstream readiness check via cached.
+func collectHandler(m *Metrics, cached *cachedScraper) http.HandlerFunc {
+	promHandler := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
 
-		// Constant-time comparison to prevent timing attacks
-		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(metricsUser)) == 1
-		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(metricsPass)) == 1
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.sidecarUptime.Set(time.Since(startTime).Seconds())
 
-		if !userMatch || !passMatch {
-			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-			return
+		for _, result := range cached.Scrape(r.Context()) {
+			m.observe(result)
 		}
 
-		next(w, r)
+		promHandler.ServeHTTP(w, r)
 	}
 }
 
-// metricsHandler serves Prometheus-formatted metrics
-func metricsHandler(w http.ResponseWriter, r *http.Request) {
-	stats, err := fetchStats()
-	if err != nil {
-		log.Printf("Error fetching stats: %v", err)
-		// Return metrics with error indicator
-		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		fmt.Fprintf(w, "# HELP delerium_up Whether the metrics sidecar can reach the main server\n")
-		fmt.Fprintf(w, "# TYPE delerium_up gauge\n")
-		fmt.Fprintf(w, "delerium_up 0\n")
-		return
+// upstreamCheck reports an error when every configured backend is
+// currently unreachable. It reuses the cached scraper so /ready never
+// triggers its own extra round of requests against the backends.
+func upstreamCheck(cached *cachedScraper) Check {
+	return Check{
+		Name:     "upstream",
+		Interval: scrapeCacheTTL,
+		Timeout:  defaultScrapeTimeout,
+		Run: func(ctx context.Context) error {
+			results := cached.Scrape(ctx)
+
+			failures := 0
+			for _, result := range results {
+				if result.Err != nil {
+					failures++
+				}
+			}
+			if len(results) > 0 && failures == len(results) {
+				return results[0].Err
+			}
+			return nil
+		},
 	}
+}
 
-	uptime := int64(time.Since(startTime).Seconds())
-	dbHealthy := 0
-	if stats.DatabaseHealthy {
-		dbHealthy = 1
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "9090"
 	}
 
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-
-	// Server reachability
-	fmt.Fprintf(w, "# HELP delerium_up Whether the metrics sidecar can reach the main server\n")
-	fmt.Fprintf(w, "# TYPE delerium_up gauge\n")
-	fmt.Fprintf(w, "delerium_up 1\n\n")
+	targets := loadTargets()
+	scraper := NewScraper(targets, defaultScrapeTimeout, scrapeConcurrency())
+	cached := newCachedScraper(scraper, scrapeCacheTTL, defaultScrapeTimeout)
+	metrics := NewMetrics()
 
-	// Sidecar uptime
-	fmt.Fprintf(w, "# HELP delerium_sidecar_uptime_seconds Metrics sidecar uptime in seconds\n")
-	fmt.Fprintf(w, "# TYPE delerium_sidecar_uptime_seconds gauge\n")
-	fmt.Fprintf(w, "delerium_sidecar_uptime_seconds %d\n\n", uptime)
+	authenticator, tlsConfig, err := buildAuthenticator()
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
 
-	// Active pastes gauge
-	fmt.Fprintf(w, "# HELP delerium_pastes_active Current number of non-expired pastes\n")
-	fmt.Fprintf(w, "# TYPE delerium_pastes_active gauge\n")
-	fmt.Fprintf(w, "delerium_pastes_active %d\n\n", stats.ActivePasteCount)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	// Total chat messages gauge
-	fmt.Fprintf(w, "# HELP delerium_chat_messages_total Total chat messages in database\n")
-	fmt.Fprintf(w, "# TYPE delerium_chat_messages_total gauge\n")
-	fmt.Fprintf(w, "delerium_chat_messages_total %d\n\n", stats.TotalChatMessages)
+	checker := NewChecker(upstreamCheck(cached))
+	checker.Run(ctx)
 
-	// Database health gauge
-	fmt.Fprintf(w, "# HELP delerium_database_healthy Database health status (1=healthy, 0=unhealthy)\n")
-	fmt.Fprintf(w, "# TYPE delerium_database_healthy gauge\n")
-	fmt.Fprintf(w, "delerium_database_healthy %d\n", dbHealthy)
-}
+	http.HandleFunc("/metrics", requireAuth(authenticator, collectHandler(metrics, cached)))
+	http.HandleFunc("/live", liveHandler)
+	http.HandleFunc("/ready", readyHandler(checker))
+	http.HandleFunc("/sd", requireAuth(authenticator, sdHandler(targets)))
 
-// healthHandler provides a simple health check for the sidecar itself
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"ok"}`)
-}
+	if cfg, enabled := loadPushConfig(); enabled {
+		go runPusher(ctx, cfg, metrics, scraper)
+	}
 
-func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "9090"
+	log.Printf("Metrics sidecar starting on :%s", port)
+	for _, t := range targets {
+		log.Printf("Scraping backend %q at: %s/internal/stats", t.Name, t.URL)
 	}
 
-	http.HandleFunc("/metrics", basicAuth(metricsHandler))
-	http.HandleFunc("/health", healthHandler) // Health check doesn't require auth
+	server := &http.Server{Addr: ":" + port, TLSConfig: tlsConfig}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			certFile := os.Getenv("METRICS_TLS_CERT_FILE")
+			keyFile := os.Getenv("METRICS_TLS_KEY_FILE")
+			if certFile == "" || keyFile == "" {
+				serveErr <- errors.New("METRICS_AUTH_MODE=mtls requires METRICS_TLS_CERT_FILE and METRICS_TLS_KEY_FILE")
+				return
+			}
+			serveErr <- server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
 
-	log.Printf("Metrics sidecar starting on :%s", port)
-	log.Printf("Fetching stats from: %s/internal/stats", serverURL)
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	case <-ctx.Done():
+		log.Printf("Received shutdown signal, draining in-flight requests")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error during graceful shutdown: %v", err)
+		}
 	}
+
+	log.Printf("Metrics sidecar stopped")
 }
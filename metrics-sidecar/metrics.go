@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Metrics holds all Prometheus collectors registered by the sidecar. Metrics
+// that describe a scraped backend are labeled by "instance"; metrics about
+// the sidecar itself (uptime, Go/process collectors) are not.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	sidecarUptime prometheus.Gauge
+
+	up              *prometheus.GaugeVec
+	pastesActive    *prometheus.GaugeVec
+	chatMessages    *prometheus.GaugeVec
+	databaseHealthy *prometheus.GaugeVec
+	scrapeDuration  *prometheus.HistogramVec
+	scrapeErrors    *prometheus.CounterVec
+
+	aggregates *aggregateCollector
+}
+
+// NewMetrics builds a fresh registry with the delerium_* collectors plus the
+// standard Go runtime and process collectors for the sidecar itself.
+func NewMetrics() *Metrics {
+	reg := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: reg,
+		sidecarUptime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "delerium_sidecar_uptime_seconds",
+			Help: "Metrics sidecar uptime in seconds",
+		}),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "delerium_up",
+			Help: "Whether the metrics sidecar can reach this backend",
+		}, []string{"instance"}),
+		pastesActive: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "delerium_pastes_active",
+			Help: "Current number of non-expired pastes",
+		}, []string{"instance"}),
+		chatMessages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "delerium_chat_messages_total",
+			Help: "Total chat messages in database",
+		}, []string{"instance"}),
+		databaseHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "delerium_database_healthy",
+			Help: "Database health status (1=healthy, 0=unhealthy)",
+		}, []string{"instance"}),
+		scrapeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "delerium_scrape_duration_seconds",
+			Help:    "Time taken to fetch stats from a backend's internal stats endpoint",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"instance"}),
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "delerium_scrape_errors_total",
+			Help: "Total number of failed scrapes of a backend's internal stats endpoint",
+		}, []string{"instance"}),
+		aggregates: newAggregateCollector(),
+	}
+
+	reg.MustRegister(
+		m.sidecarUptime,
+		m.up,
+		m.pastesActive,
+		m.chatMessages,
+		m.databaseHealthy,
+		m.scrapeDuration,
+		m.scrapeErrors,
+		m.aggregates,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// observe records a single backend's scrape result. A failure on one
+// instance only zeroes that instance's delerium_up - it never touches the
+// gauges of any other instance.
+func (m *Metrics) observe(result ScrapeResult) {
+	instance := result.Target.Name
+	m.scrapeDuration.WithLabelValues(instance).Observe(result.Duration.Seconds())
+
+	if result.Err != nil {
+		log.Printf("Error fetching stats from %q: %v", instance, result.Err)
+		m.up.WithLabelValues(instance).Set(0)
+		m.scrapeErrors.WithLabelValues(instance).Inc()
+		return
+	}
+
+	m.up.WithLabelValues(instance).Set(1)
+	m.pastesActive.WithLabelValues(instance).Set(float64(result.Stats.ActivePasteCount))
+	m.chatMessages.WithLabelValues(instance).Set(float64(result.Stats.TotalChatMessages))
+	if result.Stats.DatabaseHealthy {
+		m.databaseHealthy.WithLabelValues(instance).Set(1)
+	} else {
+		m.databaseHealthy.WithLabelValues(instance).Set(0)
+	}
+
+	m.aggregates.observe(instance, aggregateStats{
+		PasteSizeBytes:       result.Stats.PasteSizeBytes,
+		PasteTTLSeconds:      result.Stats.PasteTTLSeconds,
+		ChatMessageLength:    result.Stats.ChatMessageLength,
+		PastesByLanguage:     result.Stats.PastesByLanguage,
+		PastesByExpiryBucket: result.Stats.PastesByExpiryBucket,
+	})
+}
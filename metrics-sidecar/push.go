@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const defaultPushInterval = 15 * time.Second
+
+// pushConfig is loaded from the PUSHGATEWAY_URL/PUSH_INTERVAL/PUSH_INSTANCE
+// environment variables. Push mode is disabled unless PUSHGATEWAY_URL is set.
+type pushConfig struct {
+	url      string
+	interval time.Duration
+	instance string
+}
+
+func loadPushConfig() (pushConfig, bool) {
+	url := os.Getenv("PUSHGATEWAY_URL")
+	if url == "" {
+		return pushConfig{}, false
+	}
+
+	interval := defaultPushInterval
+	if raw := os.Getenv("PUSH_INTERVAL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			interval = d
+		}
+	}
+
+	instance := os.Getenv("PUSH_INSTANCE")
+	if instance == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			instance = hostname
+		} else {
+			instance = "delerium-sidecar-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		}
+	}
+
+	return pushConfig{url: url, interval: interval, instance: instance}, true
+}
+
+// runPusher periodically scrapes every configured backend and pushes the
+// resulting metric set to a Pushgateway, for environments where Prometheus
+// cannot reach the sidecar directly (ephemeral/batch jobs). It shares the
+// same Scraper and Metrics registry used by the pull-based /metrics
+// endpoint, and runs until ctx is canceled.
+func runPusher(ctx context.Context, cfg pushConfig, m *Metrics, scraper *Scraper) {
+	pusher := push.New(cfg.url, "delerium").
+		Grouping("instance", cfg.instance).
+		Gatherer(m.registry)
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	log.Printf("Pushing metrics to %s every %s as instance %q", cfg.url, cfg.interval, cfg.instance)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sidecarUptime.Set(time.Since(startTime).Seconds())
+			for _, result := range scraper.ScrapeAll(ctx) {
+				m.observe(result)
+			}
+
+			if err := pusher.Push(); err != nil {
+				log.Printf("Error pushing metrics to %s: %v", cfg.url, err)
+			}
+		}
+	}
+}
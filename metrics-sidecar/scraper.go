@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// InternalStats matches the JSON response from /internal/stats. The
+// histogram and counter-map fields are optional: older servers that don't
+// send them simply leave the sidecar emitting the gauges above, nothing
+// more.
+type InternalStats struct {
+	ActivePasteCount  int64 `json:"activePasteCount"`
+	TotalChatMessages int64 `json:"totalChatMessages"`
+	DatabaseHealthy   bool  `json:"databaseHealthy"`
+	TimestampMs       int64 `json:"timestampMs"`
+
+	PasteSizeBytes    *HistogramStats `json:"pasteSizeBytes,omitempty"`
+	PasteTTLSeconds   *HistogramStats `json:"pasteTTLSeconds,omitempty"`
+	ChatMessageLength *HistogramStats `json:"chatMessageLength,omitempty"`
+
+	PastesByLanguage     map[string]int64 `json:"pastesByLanguage,omitempty"`
+	PastesByExpiryBucket map[string]int64 `json:"pastesByExpiryBucket,omitempty"`
+}
+
+// HistogramStats is a pre-aggregated histogram reported by the server:
+// cumulative bucket counts keyed by upper bound ("le"), matching
+// Prometheus' own bucket semantics.
+type HistogramStats struct {
+	Buckets map[string]uint64 `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   uint64            `json:"count"`
+}
+
+// ScrapeResult is the outcome of scraping a single target.
+type ScrapeResult struct {
+	Target   Target
+	Stats    *InternalStats
+	Err      error
+	Duration time.Duration
+}
+
+// Scraper fetches /internal/stats from one or more Delirium Paste instances
+// concurrently, bounded by a semaphore so a large SERVER_URLS list can't
+// open unbounded connections at once.
+type Scraper struct {
+	client  *http.Client
+	targets []Target
+	timeout time.Duration
+	sem     chan struct{}
+}
+
+// NewScraper builds a Scraper for the given targets. concurrency bounds how
+// many targets are scraped in parallel; timeout applies per target.
+func NewScraper(targets []Target, timeout time.Duration, concurrency int) *Scraper {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Scraper{
+		client:  &http.Client{Timeout: timeout},
+		targets: targets,
+		timeout: timeout,
+		sem:     make(chan struct{}, concurrency),
+	}
+}
+
+// ScrapeAll fetches stats from every configured target in parallel. A
+// failure on one target does not affect the others; each result carries
+// its own error.
+func (s *Scraper) ScrapeAll(ctx context.Context) []ScrapeResult {
+	results := make([]ScrapeResult, len(s.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range s.targets {
+		i, target := i, target
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			s.sem <- struct{}{}
+			defer func() { <-s.sem }()
+
+			start := time.Now()
+			stats, err := s.fetchStats(ctx, target)
+			results[i] = ScrapeResult{Target: target, Stats: stats, Err: err, Duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// fetchStats retrieves stats from a single target's internal endpoint.
+func (s *Scraper) fetchStats(ctx context.Context, target Target) (*InternalStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL+"/internal/stats", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var stats InternalStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse stats: %w", err)
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// sdTargetGroup is one entry of the Prometheus HTTP service discovery
+// format: https://prometheus.io/docs/prometheus/latest/http_sd/
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// sdHandler serves the configured backends in the Prometheus HTTP SD JSON
+// format, so operators can point http_sd_configs at the sidecar instead of
+// hand-maintaining a static target list.
+func sdHandler(targets []Target) http.HandlerFunc {
+	groups := make([]sdTargetGroup, 0, len(targets))
+	for _, t := range targets {
+		host := t.URL
+		if u, err := url.Parse(t.URL); err == nil && u.Host != "" {
+			host = u.Host
+		} else {
+			log.Printf("WARNING: could not parse target URL %q for /sd, using it as-is", t.URL)
+		}
+
+		groups = append(groups, sdTargetGroup{
+			Targets: []string{host},
+			Labels: map[string]string{
+				"__meta_delerium_instance": t.Name,
+			},
+		})
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(groups); err != nil {
+			log.Printf("Error encoding /sd response: %v", err)
+			http.Error(w, "failed to encode service discovery response", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "strings"
+
+// Target is one Delirium Paste backend instance the sidecar scrapes.
+type Target struct {
+	Name string
+	URL  string
+}
+
+// parseTargets parses a comma-separated SERVER_URLS value. Each entry is
+// either a bare URL ("http://a:8080") or a "name=url" pair
+// ("prod-eu=http://a:8080"). Bare URLs are named after themselves so they
+// still produce a stable instance label. An entry is split at its first "="
+// unless that "=" is the first character, so a bare URL containing "=" (for
+// example in its query string) is read as a name=url pair.
+func parseTargets(raw string) []Target {
+	var targets []Target
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, url := entry, entry
+		if idx := strings.Index(entry, "="); idx > 0 {
+			name = entry[:idx]
+			url = entry[idx+1:]
+		}
+
+		targets = append(targets, Target{Name: name, URL: strings.TrimRight(url, "/")})
+	}
+	return targets
+}